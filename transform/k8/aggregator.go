@@ -0,0 +1,60 @@
+package k8
+
+import (
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Aggregator ties pod tracking to LogPipeline routing: it resolves the pod
+// a log line came from via the tracker, then asks the Router which
+// pipelines (parsing rule + sinks) apply to that pod. This is the
+// replacement for a static config file — log-collection code should call
+// RouteByName or RouteByIP instead of consulting a file on disk.
+type Aggregator struct {
+	tracker *podTracker
+	router  *Router
+}
+
+// newAggregator builds the tracker and router together, since routing a
+// log line always starts from "which pod did this come from".
+func newAggregator(client *kubernetes.Clientset, dynamicClient dynamic.Interface, config TrackerConfig) (*Aggregator, error) {
+	t, err := newPodTracker(client, config)
+	if err != nil {
+		return nil, err
+	}
+	return &Aggregator{
+		tracker: t,
+		router:  newRouter(dynamicClient),
+	}, nil
+}
+
+// Start begins watching pods and LogPipelines and blocks until both local
+// stores have completed their initial sync, or cacheSyncTimeout elapses.
+// Both watches are started unconditionally: a slow pod cache sync must not
+// prevent the Router's informer from starting, or vice versa.
+func (a *Aggregator) Start() bool {
+	podsSynced := a.tracker.watchForPods()
+	pipelinesSynced := a.router.watchForPipelines()
+	return podsSynced && pipelinesSynced
+}
+
+// RouteByName resolves namespaceName/podName to a pod via the tracker, then
+// returns the LogPipelines that pod matches. It returns no pipelines, no
+// error, if the pod isn't currently tracked.
+func (a *Aggregator) RouteByName(namespaceName, podName string) ([]LogPipeline, error) {
+	pod := a.tracker.Get(namespaceName, podName)
+	if pod == nil {
+		return nil, nil
+	}
+	return a.router.Match(pod)
+}
+
+// RouteByIP is RouteByName for log records that only carry a source IP,
+// e.g. from CNI/flow logs.
+func (a *Aggregator) RouteByIP(ip string) ([]LogPipeline, error) {
+	pod := a.tracker.GetByIP(ip)
+	if pod == nil {
+		return nil, nil
+	}
+	return a.router.Match(pod)
+}
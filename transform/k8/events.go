@@ -0,0 +1,118 @@
+package k8
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultEventBufferSize is used when TrackerConfig.EventBufferSize is zero.
+const defaultEventBufferSize = 10
+
+// PodEventPhase is the lifecycle phase a PodEvent records.
+type PodEventPhase string
+
+const (
+	PodEventAdd    PodEventPhase = "ADD"
+	PodEventUpdate PodEventPhase = "UPD"
+	PodEventDelete PodEventPhase = "DEL"
+)
+
+// PodEvent is a single lifecycle transition observed for a pod. Downstream
+// consumers use these to annotate log records, e.g. "pod restarted 3s
+// before this log line" when correlating crash loops with log output.
+type PodEvent struct {
+	Phase     PodEventPhase
+	Time      time.Time
+	Namespace string
+	Name      string
+	Node      string
+	UID       string
+}
+
+// podEventRing keeps the last N PodEvents per pod, keyed by
+// "namespace/name". It's bounded per key rather than globally, since a
+// single noisy pod shouldn't push the events for every other pod out of
+// the buffer.
+type podEventRing struct {
+	mu              sync.Mutex
+	size            int
+	events          map[string][]PodEvent
+	pendingRemovals map[string]*time.Timer
+}
+
+func newPodEventRing(size int) *podEventRing {
+	if size <= 0 {
+		size = defaultEventBufferSize
+	}
+	return &podEventRing{
+		size:            size,
+		events:          map[string][]PodEvent{},
+		pendingRemovals: map[string]*time.Timer{},
+	}
+}
+
+func (r *podEventRing) add(key string, ev PodEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// A new event for this key means the pod is alive (or alive again, e.g.
+	// a StatefulSet pod recreated under the same name), so any removal
+	// scheduled by a prior delete no longer applies.
+	r.cancelPendingRemovalLocked(key)
+
+	events := append(r.events[key], ev)
+	if len(events) > r.size {
+		events = events[len(events)-r.size:]
+	}
+	r.events[key] = events
+}
+
+func (r *podEventRing) get(key string) []PodEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events[key]
+	out := make([]PodEvent, len(events))
+	copy(out, events)
+	return out
+}
+
+// remove drops a key's events entirely. Without this, events accumulates one
+// permanent entry per namespace/name ever seen for the life of the process.
+func (r *podEventRing) remove(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelPendingRemovalLocked(key)
+	delete(r.events, key)
+}
+
+// scheduleRemove removes key's events after d, unless a newer add or
+// scheduleRemove for the same key supersedes it first. Keying the pending
+// timer by identity (rather than just firing a bare time.AfterFunc) means a
+// stale timer from an earlier delete can never wipe out events recorded by
+// a pod that was recreated under the same name in the meantime.
+func (r *podEventRing) scheduleRemove(key string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelPendingRemovalLocked(key)
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.pendingRemovals[key] != timer {
+			// Superseded by a later add or scheduleRemove; do nothing.
+			return
+		}
+		delete(r.events, key)
+		delete(r.pendingRemovals, key)
+	})
+	r.pendingRemovals[key] = timer
+}
+
+// cancelPendingRemovalLocked stops and clears any scheduled removal for key.
+// Callers must hold r.mu.
+func (r *podEventRing) cancelPendingRemovalLocked(key string) {
+	if timer, ok := r.pendingRemovals[key]; ok {
+		timer.Stop()
+		delete(r.pendingRemovals, key)
+	}
+}
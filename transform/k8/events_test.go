@@ -0,0 +1,88 @@
+package k8
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPodEventRing_AddTrims(t *testing.T) {
+	r := newPodEventRing(3)
+	key := "default/web-1"
+
+	for i := 0; i < 5; i++ {
+		r.add(key, PodEvent{Phase: PodEventUpdate, Time: time.Unix(int64(i), 0)})
+	}
+
+	events := r.get(key)
+	if len(events) != 3 {
+		t.Fatalf("len(events) = %d, want 3", len(events))
+	}
+	// The ring keeps the most recent N events, oldest first.
+	for i, ev := range events {
+		want := int64(i + 2)
+		if ev.Time.Unix() != want {
+			t.Errorf("events[%d].Time = %d, want %d", i, ev.Time.Unix(), want)
+		}
+	}
+}
+
+func TestPodEventRing_DefaultSize(t *testing.T) {
+	r := newPodEventRing(0)
+	if r.size != defaultEventBufferSize {
+		t.Errorf("size = %d, want default %d", r.size, defaultEventBufferSize)
+	}
+}
+
+func TestPodEventRing_GetMissingKeyIsEmpty(t *testing.T) {
+	r := newPodEventRing(3)
+	if events := r.get("nothing/here"); len(events) != 0 {
+		t.Errorf("get of missing key = %v, want empty", events)
+	}
+}
+
+func TestPodEventRing_Remove(t *testing.T) {
+	r := newPodEventRing(3)
+	key := "default/web-1"
+	r.add(key, PodEvent{Phase: PodEventDelete})
+
+	r.remove(key)
+
+	if events := r.get(key); len(events) != 0 {
+		t.Errorf("get after remove = %v, want empty", events)
+	}
+	if _, ok := r.events[key]; ok {
+		t.Error("remove should delete the map entry entirely, not just empty it")
+	}
+}
+
+func TestPodEventRing_ScheduleRemove(t *testing.T) {
+	r := newPodEventRing(3)
+	key := "default/web-1"
+	r.add(key, PodEvent{Phase: PodEventAdd})
+
+	r.scheduleRemove(key, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if events := r.get(key); len(events) != 0 {
+		t.Errorf("get after scheduled removal fired = %v, want empty", events)
+	}
+}
+
+// TestPodEventRing_AddCancelsPendingRemoval guards against a regression
+// where a StatefulSet-style pod recreated under the same name within the
+// grace window has its fresh events wiped out by the stale delete's timer.
+func TestPodEventRing_AddCancelsPendingRemoval(t *testing.T) {
+	r := newPodEventRing(3)
+	key := "default/web-1"
+	r.add(key, PodEvent{Phase: PodEventDelete})
+	r.scheduleRemove(key, 10*time.Millisecond)
+
+	// The pod comes back under the same name before the removal fires.
+	r.add(key, PodEvent{Phase: PodEventAdd})
+
+	time.Sleep(30 * time.Millisecond)
+
+	if events := r.get(key); len(events) != 2 {
+		t.Fatalf("events after stale removal window = %v, want the 2 events recorded, none pruned", events)
+	}
+}
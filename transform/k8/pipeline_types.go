@@ -0,0 +1,84 @@
+package k8
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogPipelineGroupVersion is the CRD group/version LogPipeline objects are
+// registered under.
+const LogPipelineGroupVersion = "logs.aggregator.io/v1"
+
+// ParsingRuleType selects how a pipeline parses raw log lines before they're
+// handed to its sinks.
+type ParsingRuleType string
+
+const (
+	ParsingRuleJSON  ParsingRuleType = "json"
+	ParsingRuleRegex ParsingRuleType = "regex"
+)
+
+// ParsingRule describes how to parse a log line. Pattern is the regexp when
+// Type is ParsingRuleRegex, and is unused for ParsingRuleJSON.
+type ParsingRule struct {
+	Type    ParsingRuleType `json:"type"`
+	Pattern string          `json:"pattern,omitempty"`
+}
+
+// KafkaSink publishes matched log lines to a Kafka topic.
+type KafkaSink struct {
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+}
+
+// S3Sink writes matched log lines under a prefix in an S3 bucket.
+type S3Sink struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ElasticsearchSink indexes matched log lines into an Elasticsearch index.
+type ElasticsearchSink struct {
+	Addresses []string `json:"addresses"`
+	Index     string   `json:"index"`
+}
+
+// SinkConfig is a single pipeline's destination. Exactly one field is
+// expected to be set.
+type SinkConfig struct {
+	Kafka         *KafkaSink         `json:"kafka,omitempty"`
+	S3            *S3Sink            `json:"s3,omitempty"`
+	Elasticsearch *ElasticsearchSink `json:"elasticsearch,omitempty"`
+}
+
+// LogPipelineSpec selects a set of pods by namespace and label, describes
+// how to parse their logs, and lists the sinks matched logs are sent to.
+type LogPipelineSpec struct {
+	// Namespaces, if non-empty, restricts this pipeline to the listed
+	// namespaces. Empty means all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// LabelSelector restricts this pipeline to pods matching it, using the
+	// standard Kubernetes label selector string format.
+	LabelSelector string `json:"labelSelector,omitempty"`
+
+	ParsingRule ParsingRule  `json:"parsingRule"`
+	Sinks       []SinkConfig `json:"sinks"`
+}
+
+// LogPipeline is the CRD (logs.aggregator.io/v1) that declaratively routes
+// pod logs to parsing rules and sinks, replacing a static config file.
+type LogPipeline struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec LogPipelineSpec `json:"spec"`
+}
+
+// LogPipelineList is a list of LogPipeline resources, the shape the API
+// server returns from LIST/WATCH calls.
+type LogPipelineList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []LogPipeline `json:"items"`
+}
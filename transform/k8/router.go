@@ -0,0 +1,128 @@
+package k8
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/pkg/api/v1"
+	kcache "k8s.io/client-go/tools/cache"
+)
+
+// logPipelineResource is the GroupVersionResource LogPipeline objects are
+// served under by the API server once the CRD is installed.
+var logPipelineResource = schema.GroupVersionResource{
+	Group:    "logs.aggregator.io",
+	Version:  "v1",
+	Resource: "logpipelines",
+}
+
+// Router watches LogPipeline CRDs and, given a pod, returns the pipelines
+// whose namespace/label selectors match it. It replaces the static config
+// file with declarative, in-cluster configuration.
+type Router struct {
+	informer kcache.SharedIndexInformer
+}
+
+// newRouter builds an informer over LogPipeline objects cluster-wide via a
+// dynamic client, since the aggregator doesn't carry a generated clientset
+// for its own CRD. The informer is not run until watchForPipelines.
+func newRouter(dynamicClient dynamic.Interface) *Router {
+	listWatch := &kcache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return dynamicClient.Resource(logPipelineResource).Namespace(v1.NamespaceAll).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return dynamicClient.Resource(logPipelineResource).Namespace(v1.NamespaceAll).Watch(options)
+		},
+	}
+
+	r := &Router{}
+	r.informer = kcache.NewSharedIndexInformer(
+		listWatch,
+		&unstructured.Unstructured{},
+		resyncPeriod,
+		kcache.Indexers{kcache.NamespaceIndex: kcache.MetaNamespaceIndexFunc},
+	)
+	return r
+}
+
+// watchForPipelines starts the informer and blocks until its local store has
+// completed its initial sync, or cacheSyncTimeout elapses.
+func (r *Router) watchForPipelines() bool {
+	stopCh := make(chan struct{})
+	go r.informer.Run(stopCh)
+	return kcache.WaitForCacheSync(timeoutCh(cacheSyncTimeout), r.informer.HasSynced)
+}
+
+// Match returns every LogPipeline whose namespace and label selector match
+// pod, along with any conversion errors encountered along the way (a
+// malformed LogPipeline shouldn't silently drop other, valid pipelines).
+func (r *Router) Match(pod *v1.Pod) ([]LogPipeline, error) {
+	var matched []LogPipeline
+	var errs []error
+	for _, obj := range r.informer.GetStore().List() {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		pipeline, err := toLogPipeline(u)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "converting LogPipeline %s", u.GetName()))
+			continue
+		}
+		if pipelineMatchesPod(pipeline, pod) {
+			matched = append(matched, *pipeline)
+		}
+	}
+	if len(errs) > 0 {
+		return matched, errors.Errorf("%d LogPipeline(s) failed to convert: %v", len(errs), errs)
+	}
+	return matched, nil
+}
+
+func toLogPipeline(u *unstructured.Unstructured) (*LogPipeline, error) {
+	var pipeline LogPipeline
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), &pipeline); err != nil {
+		return nil, err
+	}
+	return &pipeline, nil
+}
+
+func pipelineMatchesPod(pipeline *LogPipeline, pod *v1.Pod) bool {
+	if len(pipeline.Spec.Namespaces) > 0 {
+		found := false
+		for _, ns := range pipeline.Spec.Namespaces {
+			if ns == pod.Namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if pipeline.Spec.LabelSelector == "" {
+		return true
+	}
+	selector, err := labels.Parse(pipeline.Spec.LabelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(pod.Labels))
+}
+
+func timeoutCh(d time.Duration) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		time.Sleep(d)
+		close(ch)
+	}()
+	return ch
+}
@@ -0,0 +1,137 @@
+package k8
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/pkg/api/v1"
+)
+
+func TestToLogPipeline(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": LogPipelineGroupVersion,
+			"kind":       "LogPipeline",
+			"metadata": map[string]interface{}{
+				"name": "checkout-json",
+			},
+			"spec": map[string]interface{}{
+				"namespaces":    []interface{}{"checkout"},
+				"labelSelector": "app=checkout",
+				"parsingRule": map[string]interface{}{
+					"type": "json",
+				},
+				"sinks": []interface{}{
+					map[string]interface{}{
+						"kafka": map[string]interface{}{
+							"brokers": []interface{}{"kafka:9092"},
+							"topic":   "checkout-logs",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	pipeline, err := toLogPipeline(u)
+	if err != nil {
+		t.Fatalf("toLogPipeline returned error: %v", err)
+	}
+	if pipeline.Name != "checkout-json" {
+		t.Errorf("Name = %q, want %q", pipeline.Name, "checkout-json")
+	}
+	if pipeline.Spec.ParsingRule.Type != ParsingRuleJSON {
+		t.Errorf("ParsingRule.Type = %q, want %q", pipeline.Spec.ParsingRule.Type, ParsingRuleJSON)
+	}
+	if len(pipeline.Spec.Sinks) != 1 || pipeline.Spec.Sinks[0].Kafka == nil {
+		t.Fatalf("Sinks = %+v, want one Kafka sink", pipeline.Spec.Sinks)
+	}
+	if pipeline.Spec.Sinks[0].Kafka.Topic != "checkout-logs" {
+		t.Errorf("Kafka.Topic = %q, want %q", pipeline.Spec.Sinks[0].Kafka.Topic, "checkout-logs")
+	}
+}
+
+func TestToLogPipeline_Malformed(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				// namespaces should be a []interface{} of strings, not a bool.
+				"namespaces": true,
+			},
+		},
+	}
+	if _, err := toLogPipeline(u); err == nil {
+		t.Fatal("expected an error converting a malformed LogPipeline, got nil")
+	}
+}
+
+func TestPipelineMatchesPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline LogPipeline
+		pod      v1.Pod
+		want     bool
+	}{
+		{
+			name:     "no selector matches everything",
+			pipeline: LogPipeline{},
+			pod:      v1.Pod{},
+			want:     true,
+		},
+		{
+			name: "namespace allow list excludes",
+			pipeline: LogPipeline{
+				Spec: LogPipelineSpec{Namespaces: []string{"checkout"}},
+			},
+			pod:  v1.Pod{},
+			want: false,
+		},
+		{
+			name: "namespace allow list includes",
+			pipeline: LogPipeline{
+				Spec: LogPipelineSpec{Namespaces: []string{"checkout"}},
+			},
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "checkout"},
+			},
+			want: true,
+		},
+		{
+			name: "label selector excludes",
+			pipeline: LogPipeline{
+				Spec: LogPipelineSpec{LabelSelector: "app=checkout"},
+			},
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "payments"}},
+			},
+			want: false,
+		},
+		{
+			name: "label selector includes",
+			pipeline: LogPipeline{
+				Spec: LogPipelineSpec{LabelSelector: "app=checkout"},
+			},
+			pod: v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "checkout"}},
+			},
+			want: true,
+		},
+		{
+			name: "unparseable label selector never matches",
+			pipeline: LogPipeline{
+				Spec: LogPipelineSpec{LabelSelector: "not a valid selector!!"},
+			},
+			pod:  v1.Pod{},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pipelineMatchesPod(&tt.pipeline, &tt.pod); got != tt.want {
+				t.Errorf("pipelineMatchesPod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,35 +1,124 @@
 package k8
 
 import (
-	"fmt"
 	"time"
 
-	lru "github.com/hashicorp/golang-lru"
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/pkg/api/v1"
 	kcache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2/klogr"
 )
 
 const (
 	// Resync period for the kube controller loop.
 	resyncPeriod = 30 * time.Minute
+
+	// CollectAnnotation, when set to "true" on a pod, opts that pod into
+	// tracking when RequireCollectAnnotation is set; the pod must still pass
+	// the namespace, label and NodeSelectorMode checks. Annotations aren't part of
+	// the Kubernetes selector grammar, so this can only be enforced
+	// client-side in canTrackPod, not pushed into the ListWatch.
+	CollectAnnotation = "log-aggregator.io/collect"
+
+	// cacheSyncTimeout bounds how long watchForPods waits for the informer's
+	// local store to do its initial sync before giving up.
+	cacheSyncTimeout = 30 * time.Second
+
+	// indexPodIP and indexPodUID are the SharedIndexInformer indexer names
+	// backing GetByIP and GetByUID.
+	indexPodIP  = "podIP"
+	indexPodUID = "podUID"
+
+	// eventRetentionAfterDelete is how long a deleted pod's events stay
+	// queryable via Events before podEventRing.scheduleRemove prunes them. Without
+	// this, events would grow one permanent entry per namespace/name ever
+	// seen for the life of the process.
+	eventRetentionAfterDelete = 5 * time.Minute
 )
 
+// NodeSelectorMode controls which pods a podTracker considers itself
+// responsible for.
+type NodeSelectorMode int
+
+const (
+	// NodeSelectorModeSingleNode tracks only pods scheduled onto NodeName,
+	// the traditional DaemonSet-sidecar deployment.
+	NodeSelectorModeSingleNode NodeSelectorMode = iota
+	// NodeSelectorModeAllNodes tracks every pod in the cluster, for a
+	// centralized aggregator running outside the cluster's DaemonSets.
+	NodeSelectorModeAllNodes
+	// NodeSelectorModeNodeLabel tracks pods scheduled onto any node
+	// matching NodeLabelSelector.
+	NodeSelectorModeNodeLabel
+)
+
+// TrackerConfig configures which pods a podTracker watches and tracks. It
+// lets a single binary serve both per-node sidecar deployments and a
+// centralized, cluster-wide aggregator.
+type TrackerConfig struct {
+	// NodeName is the node this tracker is running on. Required for
+	// NodeSelectorModeSingleNode, ignored otherwise.
+	NodeName string
+
+	// NodeSelectorMode picks how pods are matched against nodes.
+	NodeSelectorMode NodeSelectorMode
+
+	// NodeLabelSelector selects nodes when NodeSelectorMode is
+	// NodeSelectorModeNodeLabel.
+	NodeLabelSelector labels.Selector
+
+	// Namespaces, if non-empty, is the allow list of namespaces to track.
+	// When empty, all namespaces are allowed unless denied below.
+	Namespaces []string
+
+	// ExcludeNamespaces is a deny list of namespaces to never track.
+	ExcludeNamespaces []string
+
+	// LabelSelector, if non-nil, restricts tracking to pods matching it.
+	// It is pushed into the ListWatch so the API server filters server-side.
+	LabelSelector labels.Selector
+
+	// RequireCollectAnnotation, when true, only tracks pods that carry
+	// CollectAnnotation="true".
+	RequireCollectAnnotation bool
+
+	// Logger receives structured lifecycle log lines (namespace, pod, node,
+	// uid, phase). Its verbosity is controlled the usual klog way, via -v.
+	// Defaults to klogr.New() when unset.
+	Logger logr.Logger
+
+	// EventBufferSize is how many lifecycle events are kept per pod in the
+	// ring buffer backing Events. Defaults to defaultEventBufferSize.
+	EventBufferSize int
+}
+
+// tracker looks up tracked pods by the identifiers log records carry: the
+// namespace/name pair from the container runtime, a source IP from CNI/flow
+// logs, or a UID from the Kubernetes downward API.
 type tracker interface {
-	Get(string, string) *v1.Pod
+	Get(namespaceName, podName string) *v1.Pod
+	GetByIP(ip string) *v1.Pod
+	GetByUID(uid string) *v1.Pod
+	Events(namespaceName, podName string) []PodEvent
 }
 
 type podTracker struct {
-	client *kubernetes.Clientset
+	client   *kubernetes.Clientset
+	config   TrackerConfig
+	informer kcache.SharedIndexInformer
+	logger   logr.Logger
+	events   *podEventRing
 
-	// The name of the node that we are running on.
-	NodeName string
-	cache    *lru.Cache
+	nodeLabels map[string]labels.Set
 }
 
 func newK8(k8ConfigPath string) (*kubernetes.Clientset, error) {
@@ -46,50 +135,178 @@ func newK8(k8ConfigPath string) (*kubernetes.Clientset, error) {
 	return clientset, nil
 }
 
-func newPodTracker(client *kubernetes.Clientset, nodeName string, maxPods int) *podTracker {
-	cache, err := lru.New(maxPods)
-	if err != nil {
-		panic(err)
+func newPodTracker(client *kubernetes.Clientset, config TrackerConfig) (*podTracker, error) {
+	if config.NodeSelectorMode == NodeSelectorModeNodeLabel && config.NodeLabelSelector == nil {
+		return nil, errors.New("k8: NodeSelectorModeNodeLabel requires a non-nil TrackerConfig.NodeLabelSelector")
+	}
+
+	logger := config.Logger
+	if logger.GetSink() == nil {
+		logger = klogr.New()
 	}
-	return &podTracker{
-		NodeName: nodeName,
-		cache:    cache,
-		client:   client,
+
+	t := &podTracker{
+		config:     config,
+		client:     client,
+		logger:     logger.WithName("podTracker"),
+		events:     newPodEventRing(config.EventBufferSize),
+		nodeLabels: map[string]labels.Set{},
 	}
-}
 
-func (t *podTracker) watchForPods() {
-	_, podController := kcache.NewInformer(
-		kcache.NewListWatchFromClient(t.client.CoreV1().RESTClient(), "pods", v1.NamespaceAll, fields.Everything()),
+	t.informer = kcache.NewSharedIndexInformer(
+		t.listWatch(),
 		&v1.Pod{},
 		resyncPeriod,
-		kcache.ResourceEventHandlerFuncs{
-			AddFunc:    t.OnAdd,
-			DeleteFunc: t.OnDelete,
-			UpdateFunc: t.OnUpdate,
+		kcache.Indexers{
+			indexPodIP:  podIPIndexFunc,
+			indexPodUID: podUIDIndexFunc,
 		},
 	)
-	go podController.Run(wait.NeverStop)
-	return
+	t.informer.AddEventHandler(kcache.ResourceEventHandlerFuncs{
+		AddFunc:    t.OnAdd,
+		DeleteFunc: t.OnDelete,
+		UpdateFunc: t.OnUpdate,
+	})
+
+	return t, nil
+}
+
+func podIPIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Status.PodIP == "" {
+		return nil, nil
+	}
+	return []string{pod.Status.PodIP}, nil
+}
+
+func podUIDIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.UID == "" {
+		return nil, nil
+	}
+	return []string{string(pod.UID)}, nil
+}
+
+// namespaceToWatch returns the single namespace to scope the ListWatch to,
+// or v1.NamespaceAll when tracking spans more than one namespace. The API
+// server can't OR together multiple namespaces in a single list/watch call,
+// so a lone allow-listed namespace is the only case we can push server-side;
+// anything wider falls back to NamespaceAll with filtering in canTrackPod.
+func (t *podTracker) namespaceToWatch() string {
+	if len(t.config.Namespaces) == 1 {
+		return t.config.Namespaces[0]
+	}
+	return v1.NamespaceAll
 }
 
+func (t *podTracker) listOptions() metav1.ListOptions {
+	opts := metav1.ListOptions{}
+	if t.config.LabelSelector != nil {
+		opts.LabelSelector = t.config.LabelSelector.String()
+	}
+	if t.config.NodeSelectorMode == NodeSelectorModeSingleNode && t.config.NodeName != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", t.config.NodeName).String()
+	}
+	return opts
+}
+
+func (t *podTracker) listWatch() *kcache.ListWatch {
+	namespace := t.namespaceToWatch()
+	restClient := t.client.CoreV1().RESTClient()
+	return &kcache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			opts := t.listOptions()
+			opts.ResourceVersion = options.ResourceVersion
+			return restClient.Get().
+				Namespace(namespace).
+				Resource("pods").
+				VersionedParams(&opts, metav1.ParameterCodec).
+				Do().
+				Get()
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			opts := t.listOptions()
+			opts.ResourceVersion = options.ResourceVersion
+			opts.Watch = true
+			return restClient.Get().
+				Namespace(namespace).
+				Resource("pods").
+				VersionedParams(&opts, metav1.ParameterCodec).
+				Watch()
+		},
+	}
+}
+
+// watchForPods starts the informer and blocks until its local store has
+// completed its initial sync, or cacheSyncTimeout elapses. Lookups served
+// before the cache has synced would silently miss pods that exist but
+// haven't been listed yet, so callers should treat a false return as "not
+// ready" rather than "no pods exist".
+func (t *podTracker) watchForPods() bool {
+	stopCh := wait.NeverStop
+	go t.informer.Run(stopCh)
+
+	timeout := time.After(cacheSyncTimeout)
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if t.informer.HasSynced() {
+			return true
+		}
+		select {
+		case <-timeout:
+			return false
+		case <-tick.C:
+		}
+	}
+}
+
+// Get returns the tracked pod for namespaceName/podName, or nil if it
+// isn't currently tracked. The informer's store holds every pod the
+// ListWatch returns, which can be broader than what canTrackPod allows
+// (ExcludeNamespaces, RequireCollectAnnotation, and NodeSelectorModeNodeLabel
+// can't be pushed into the ListWatch), so canTrackPod is re-applied here.
 func (t *podTracker) Get(namespaceName, podName string) *v1.Pod {
-	if val, ok := t.cache.Get(t.cacheKey(namespaceName, podName)); ok {
-		return val.(*v1.Pod)
+	obj, exists, err := t.informer.GetStore().GetByKey(t.cacheKey(namespaceName, podName))
+	if err != nil || !exists {
+		return nil
+	}
+	return t.trackedPod(obj)
+}
+
+func (t *podTracker) GetByIP(ip string) *v1.Pod {
+	return t.getByIndex(indexPodIP, ip)
+}
+
+func (t *podTracker) GetByUID(uid string) *v1.Pod {
+	return t.getByIndex(indexPodUID, uid)
+}
+
+// getByIndex looks up a pod by indexer and, like Get, re-applies
+// canTrackPod before returning it.
+func (t *podTracker) getByIndex(indexName, indexedValue string) *v1.Pod {
+	objs, err := t.informer.GetIndexer().ByIndex(indexName, indexedValue)
+	if err != nil || len(objs) == 0 {
+		return nil
 	}
-	pod, err := t.client.CoreV1().Pods(namespaceName).Get(podName, metav1.GetOptions{})
-	if err == nil {
-		t.cache.ContainsOrAdd(t.cacheKey(namespaceName, podName), pod)
-		return pod
+	return t.trackedPod(objs[0])
+}
+
+// trackedPod type-asserts obj to *v1.Pod and returns it only if canTrackPod
+// still allows it, so lookups never hand back a pod that's excluded by
+// configuration the ListWatch couldn't filter server-side.
+func (t *podTracker) trackedPod(obj interface{}) *v1.Pod {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || !t.canTrackPod(pod) {
+		return nil
 	}
-	return nil
+	return pod
 }
 
 func (t *podTracker) OnAdd(obj interface{}) {
 	if pod, ok := obj.(*v1.Pod); ok {
 		if t.canTrackPod(pod) {
-			t.cache.Add(t.cacheKey(pod.Namespace, pod.Name), pod)
-			fmt.Printf("ADD %s : %s\n", pod.Namespace, pod.Name)
+			t.recordEvent(PodEventAdd, pod)
 		}
 	}
 }
@@ -104,8 +321,7 @@ func (t *podTracker) OnUpdate(oldObj, newObj interface{}) {
 		return
 	}
 	if t.canTrackPod(newPod) {
-		t.cache.Add(t.cacheKey(newPod.Namespace, newPod.Name), newPod)
-		fmt.Printf("UPD %s : %s\n", newPod.Namespace, newPod.Name)
+		t.recordEvent(PodEventUpdate, newPod)
 	}
 }
 
@@ -122,19 +338,99 @@ func (t *podTracker) OnDelete(obj interface{}) {
 	if !ok {
 		return
 	}
-	t.cache.Remove(t.cacheKey(pod.Namespace, pod.Name))
-	fmt.Printf("DEL %s : %s\n", pod.Namespace, pod.Name)
+	t.recordEvent(PodEventDelete, pod)
+	t.events.scheduleRemove(t.cacheKey(pod.Namespace, pod.Name), eventRetentionAfterDelete)
+}
+
+// recordEvent logs a lifecycle transition with structured fields and
+// appends it to the pod's event ring buffer.
+func (t *podTracker) recordEvent(phase PodEventPhase, pod *v1.Pod) {
+	t.logger.V(1).Info(string(phase),
+		"namespace", pod.Namespace,
+		"pod", pod.Name,
+		"node", pod.Spec.NodeName,
+		"uid", string(pod.UID),
+	)
+	t.events.add(t.cacheKey(pod.Namespace, pod.Name), PodEvent{
+		Phase:     phase,
+		Time:      time.Now(),
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Node:      pod.Spec.NodeName,
+		UID:       string(pod.UID),
+	})
+}
+
+// Events returns the buffered lifecycle events for the given pod, oldest
+// first, for correlating nearby pod restarts with log output.
+func (t *podTracker) Events(namespaceName, podName string) []PodEvent {
+	return t.events.get(t.cacheKey(namespaceName, podName))
 }
 
 func (t *podTracker) cacheKey(namespaceName, podName string) string {
-	return namespaceName + "_" + podName
+	return namespaceName + "/" + podName
 }
 
 func (t *podTracker) canTrackPod(pod *v1.Pod) bool {
 	if pod.Spec.NodeName == "" {
 		return false
-	} else if t.NodeName != "" && t.NodeName != pod.Spec.NodeName {
+	}
+	if !t.canTrackNamespace(pod.Namespace) {
+		return false
+	}
+	if t.config.RequireCollectAnnotation && pod.Annotations[CollectAnnotation] != "true" {
+		return false
+	}
+	if t.config.LabelSelector != nil && !t.config.LabelSelector.Matches(labels.Set(pod.Labels)) {
 		return false
 	}
+	return t.canTrackNode(pod.Spec.NodeName)
+}
+
+func (t *podTracker) canTrackNamespace(namespace string) bool {
+	if len(t.config.Namespaces) > 0 {
+		found := false
+		for _, ns := range t.config.Namespaces {
+			if ns == namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, ns := range t.config.ExcludeNamespaces {
+		if ns == namespace {
+			return false
+		}
+	}
 	return true
 }
+
+func (t *podTracker) canTrackNode(nodeName string) bool {
+	switch t.config.NodeSelectorMode {
+	case NodeSelectorModeAllNodes:
+		return true
+	case NodeSelectorModeNodeLabel:
+		return t.config.NodeLabelSelector.Matches(t.nodeLabelsFor(nodeName))
+	default:
+		return t.config.NodeName == "" || t.config.NodeName == nodeName
+	}
+}
+
+// nodeLabelsFor fetches and caches the label set for nodeName, used only in
+// NodeSelectorModeNodeLabel. Nodes churn far less than pods, so a simple
+// fetch-once cache (no informer) is sufficient here.
+func (t *podTracker) nodeLabelsFor(nodeName string) labels.Set {
+	if set, ok := t.nodeLabels[nodeName]; ok {
+		return set
+	}
+	node, err := t.client.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return labels.Set{}
+	}
+	set := labels.Set(node.Labels)
+	t.nodeLabels[nodeName] = set
+	return set
+}
@@ -0,0 +1,122 @@
+package k8
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestCanTrackNamespace(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TrackerConfig
+		ns     string
+		want   bool
+	}{
+		{
+			name:   "no lists tracks everything",
+			config: TrackerConfig{},
+			ns:     "checkout",
+			want:   true,
+		},
+		{
+			name:   "allow list excludes non-members",
+			config: TrackerConfig{Namespaces: []string{"checkout", "payments"}},
+			ns:     "logging",
+			want:   false,
+		},
+		{
+			name:   "allow list includes members",
+			config: TrackerConfig{Namespaces: []string{"checkout", "payments"}},
+			ns:     "payments",
+			want:   true,
+		},
+		{
+			name:   "deny list excludes members",
+			config: TrackerConfig{ExcludeNamespaces: []string{"kube-system"}},
+			ns:     "kube-system",
+			want:   false,
+		},
+		{
+			name: "deny list wins over allow list",
+			config: TrackerConfig{
+				Namespaces:        []string{"checkout"},
+				ExcludeNamespaces: []string{"checkout"},
+			},
+			ns:   "checkout",
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &podTracker{config: tt.config}
+			if got := tr.canTrackNamespace(tt.ns); got != tt.want {
+				t.Errorf("canTrackNamespace(%q) = %v, want %v", tt.ns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanTrackNode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TrackerConfig
+		node   string
+		want   bool
+	}{
+		{
+			name:   "single node mode matches configured node",
+			config: TrackerConfig{NodeSelectorMode: NodeSelectorModeSingleNode, NodeName: "node-a"},
+			node:   "node-a",
+			want:   true,
+		},
+		{
+			name:   "single node mode rejects other nodes",
+			config: TrackerConfig{NodeSelectorMode: NodeSelectorModeSingleNode, NodeName: "node-a"},
+			node:   "node-b",
+			want:   false,
+		},
+		{
+			name:   "single node mode with empty NodeName matches everything",
+			config: TrackerConfig{NodeSelectorMode: NodeSelectorModeSingleNode},
+			node:   "node-b",
+			want:   true,
+		},
+		{
+			name:   "all nodes mode always matches",
+			config: TrackerConfig{NodeSelectorMode: NodeSelectorModeAllNodes},
+			node:   "node-anything",
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &podTracker{config: tt.config}
+			if got := tr.canTrackNode(tt.node); got != tt.want {
+				t.Errorf("canTrackNode(%q) = %v, want %v", tt.node, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanTrackNode_NodeLabelMode(t *testing.T) {
+	tr := &podTracker{
+		config: TrackerConfig{
+			NodeSelectorMode:  NodeSelectorModeNodeLabel,
+			NodeLabelSelector: labels.SelectorFromSet(labels.Set{"zone": "us-east-1a"}),
+		},
+		nodeLabels: map[string]labels.Set{
+			"node-a": {"zone": "us-east-1a"},
+			"node-b": {"zone": "us-west-2a"},
+		},
+	}
+
+	if !tr.canTrackNode("node-a") {
+		t.Error("canTrackNode(node-a) = false, want true for matching zone label")
+	}
+	if tr.canTrackNode("node-b") {
+		t.Error("canTrackNode(node-b) = true, want false for non-matching zone label")
+	}
+}